@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CgroupReporter samples whole-cgroup memory/CPU/IO usage directly from
+// /sys/fs/cgroup, so short-lived children that a PID-only walk would miss
+// entirely (they can exit before the next sample) are still counted.
+type CgroupReporter struct {
+	path string
+	v2   bool
+
+	prevCPUSeconds float64
+	prevAt         time.Time
+}
+
+// NewCgroupReporter detects whether path is a cgroup v1 or v2 hierarchy
+// and returns a Reporter for it.
+func NewCgroupReporter(path string) (*CgroupReporter, error) {
+	if _, err := os.Stat(filepath.Join(path, "cgroup.controllers")); err == nil {
+		return &CgroupReporter{path: path, v2: true}, nil
+	}
+	if _, err := os.Stat(filepath.Join(path, "memory.stat")); err == nil {
+		return &CgroupReporter{path: path}, nil
+	}
+	return nil, fmt.Errorf("%s does not look like a cgroup v1 or v2 directory", path)
+}
+
+func (r *CgroupReporter) Label() string {
+	return filepath.Base(r.path)
+}
+
+func (r *CgroupReporter) Running() (bool, error) {
+	if _, err := os.Stat(r.path); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *CgroupReporter) Sample(t time.Duration) (sample, error) {
+	if r.v2 {
+		return r.sampleV2(t)
+	}
+	return r.sampleV1(t)
+}
+
+func (r *CgroupReporter) sampleV1(t time.Duration) (sample, error) {
+	rss, err := readCgroupStatField(filepath.Join(r.path, "memory.stat"), "rss")
+	if err != nil {
+		return sample{}, err
+	}
+	cache, _ := readCgroupStatField(filepath.Join(r.path, "memory.stat"), "cache")
+	cpuNanos, err := readCgroupUint(filepath.Join(r.path, "cpuacct.usage"))
+	if err != nil {
+		return sample{}, err
+	}
+	read, write, err := readBlkioThrottle(filepath.Join(r.path, "blkio.throttle.io_service_bytes"))
+	if err != nil {
+		return sample{}, err
+	}
+
+	return sample{
+		rss:        rss,
+		vms:        rss + cache,
+		readBytes:  read,
+		writeBytes: write,
+		cpu:        r.cpuPercent(float64(cpuNanos) / 1e9),
+		interval:   t,
+	}, nil
+}
+
+func (r *CgroupReporter) sampleV2(t time.Duration) (sample, error) {
+	rss, err := readCgroupUint(filepath.Join(r.path, "memory.current"))
+	if err != nil {
+		return sample{}, err
+	}
+	usageUsec, err := readCgroupStatField(filepath.Join(r.path, "cpu.stat"), "usage_usec")
+	if err != nil {
+		return sample{}, err
+	}
+	read, write, err := readIOStat(filepath.Join(r.path, "io.stat"))
+	if err != nil {
+		return sample{}, err
+	}
+
+	return sample{
+		rss:        rss,
+		vms:        rss,
+		readBytes:  read,
+		writeBytes: write,
+		cpu:        r.cpuPercent(float64(usageUsec) / 1e6),
+		interval:   t,
+	}, nil
+}
+
+// cpuPercent turns a cumulative CPU-seconds counter into a percentage of
+// one core over the wall time elapsed since the last sample, the same way
+// gopsutil's process.CPUPercent works for a single PID.
+func (r *CgroupReporter) cpuPercent(cumulativeSeconds float64) float64 {
+	now := time.Now()
+	defer func() {
+		r.prevCPUSeconds = cumulativeSeconds
+		r.prevAt = now
+	}()
+	if r.prevAt.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(r.prevAt).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return (cumulativeSeconds - r.prevCPUSeconds) / elapsed * 100
+}
+
+func readCgroupUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readCgroupStatField reads a "key value"-per-line file (memory.stat,
+// cpu.stat, ...) and returns the value for key.
+func readCgroupStatField(path, key string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("field %q not found in %s", key, path)
+}
+
+// readBlkioThrottle sums read/write bytes across every device line of
+// cgroup v1's blkio.throttle.io_service_bytes.
+func readBlkioThrottle(path string) (read, write uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += v
+		case "Write":
+			write += v
+		}
+	}
+	return read, write, nil
+}
+
+// readIOStat sums rbytes/wbytes across every device line of cgroup v2's
+// io.stat.
+func readIOStat(path string) (read, write uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for _, kv := range fields[1:] {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch parts[0] {
+			case "rbytes":
+				read += v
+			case "wbytes":
+				write += v
+			}
+		}
+	}
+	return read, write, nil
+}
+
+// candidateCgroupPaths are the cgroup locations common container runtimes
+// use for a given container id, checked in order for -cid.
+var candidateCgroupPaths = []string{
+	"/sys/fs/cgroup/memory/docker/%[1]s",
+	"/sys/fs/cgroup/docker/%[1]s",
+	"/sys/fs/cgroup/system.slice/docker-%[1]s.scope",
+	"/sys/fs/cgroup/system.slice/containerd-%[1]s.scope",
+}
+
+// resolveCgroupPath finds the cgroup directory for a container id by
+// checking the locations dockerd/containerd typically mount it under.
+func resolveCgroupPath(cid string) (string, error) {
+	for _, tmpl := range candidateCgroupPaths {
+		path := fmt.Sprintf(tmpl, cid)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a cgroup for container %q under /sys/fs/cgroup", cid)
+}
+
+// readCIDFile reads a container id written by e.g. `docker run --cidfile`.
+// It is re-read by the -cidfile poll loop so pinfoplot can be started
+// before the container itself exists.
+func readCIDFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	cid := strings.TrimSpace(string(data))
+	if cid == "" {
+		return "", fmt.Errorf("cidfile %s is empty", path)
+	}
+	return cid, nil
+}
+
+// waitForCgroup polls a -cid/-cidfile until it resolves to an existing
+// cgroup, or timeout elapses.
+func waitForCgroup(resolve func() (string, error), pollEvery, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		path, err := resolve()
+		if err == nil {
+			return path, nil
+		}
+		lastErr = err
+		time.Sleep(pollEvery)
+	}
+	return "", fmt.Errorf("timed out waiting for cgroup: %w", lastErr)
+}
@@ -11,142 +11,482 @@ import (
 	"gonum.org/v1/plot/vg/vgimg"
 	"image/color"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 var (
-	help     bool
-	version  bool
-	pid      int64
-	duration time.Duration
-	interval time.Duration
-	width    string
-	height   string
-	output   string
+	help        bool
+	version     bool
+	pid         int64
+	pattern     string
+	fullPattern string
+	pidfile     string
+	user        string
+	duration    time.Duration
+	interval    time.Duration
+	width       string
+	height      string
+	output      string
+	tui         bool
+	tree        bool
+	threads     bool
+	cgroupPath  string
+	cid         string
+	cidfile     string
+	formats     = formatFlag{values: []string{"png"}}
+	memMetrics  string
+	mapsTop     int
 )
 
 func init() {
 	flag.BoolVar(&help, "help", false, "help info")
 	flag.BoolVar(&version, "v", false, "version info")
 	flag.Int64Var(&pid, "p", -1, "pid to get info from")
-	flag.DurationVar(&duration, "d", 10*time.Second, "sampling duration (0 means sample until pid exits)")
+	flag.StringVar(&pattern, "pattern", "", "regex matched against process name; monitor every matching process (mutually exclusive with -fullpattern, -pidfile, -user)")
+	flag.StringVar(&fullPattern, "fullpattern", "", "regex matched against the full command line; monitor every matching process")
+	flag.StringVar(&pidfile, "pidfile", "", "path to a file containing a single pid to monitor")
+	flag.StringVar(&user, "user", "", "monitor every process owned by this user")
+	flag.DurationVar(&duration, "d", 10*time.Second, "sampling duration (0 means sample until all matched processes exit)")
 	flag.DurationVar(&interval, "i", 50*time.Millisecond, "sampling interval")
 	flag.StringVar(&width, "w", "10cm", "output image width (cm or inch)")
 	flag.StringVar(&height, "h", "8cm", "output image height (cm or inch)")
 	flag.StringVar(&output, "o", "pinfo.png", "output image file path")
+	flag.BoolVar(&tui, "tui", false, "launch a live terminal dashboard instead of writing a PNG; monitors the same -p/-pattern/-fullpattern/-pidfile/-user selection as the PNG mode, one tab per pid, switch with Tab/Shift+Tab")
+	flag.BoolVar(&tree, "tree", false, "also walk each monitored process's children recursively and aggregate their usage")
+	flag.BoolVar(&threads, "threads", false, "also sample per-thread CPU% for each monitored process (use with ThreadPlot)")
+	flag.StringVar(&cgroupPath, "cgroup", "", "monitor a cgroup v1 or v2 directory instead of a pid (mutually exclusive with -p/-pattern/.../-cid/-cidfile)")
+	flag.StringVar(&cid, "cid", "", "monitor the cgroup of this container id")
+	flag.StringVar(&cidfile, "cidfile", "", "path to a container id file (e.g. docker run --cidfile), polled until the container starts")
+	flag.Var(&formats, "format", "output format(s): png, csv, json, influx (repeatable, e.g. -format csv -format json); defaults to png")
+	flag.StringVar(&memMetrics, "mem-metrics", "rss,vms", "comma-separated memory metrics to plot in MemPlot: rss, vms, pss, uss, swap, shared, text, data, dirty")
+	flag.IntVar(&mapsTop, "maps-top", 0, "also capture the N largest memory mappings per tick for MapsPlot (0 disables)")
 	flag.Usage = usage
 }
 
 func usage() {
 	_, _ = fmt.Fprintf(os.Stderr, `pinfoplot tool in golang to generate process info image
 Version: 0.0.1
-Usage: pinfoplot [-help help] [-v version] [-p pid] [-d sampling duration] [-i sampling interval] [-w output image width (cm or inch)] [-h output image height (cm or inch)] [-o output image file path]
+Usage: pinfoplot [-help help] [-v version] [-p pid] [-pattern regex] [-fullpattern regex] [-pidfile path] [-user name] [-cgroup path] [-cid container-id] [-cidfile path] [-d sampling duration] [-i sampling interval] [-w output image width (cm or inch)] [-h output image height (cm or inch)] [-o output image file path] [-format png|csv|json|influx] [-mem-metrics rss,vms,pss,uss,swap,shared,text,data,dirty] [-maps-top N] [-tui live dashboard] [-tree aggregate children] [-threads per-thread CPU]
 Options
 `)
 	flag.PrintDefaults()
 }
 
+// buildFinder picks the PIDFinder for this run from the mutually exclusive
+// process-selection flags: -p, -pattern, -fullpattern, -pidfile, -user.
+func buildFinder() (PIDFinder, error) {
+	var finder PIDFinder
+	set := 0
+	if pattern != "" {
+		f, err := newPatternPIDFinder(pattern, false)
+		if err != nil {
+			return nil, err
+		}
+		finder = f
+		set++
+	}
+	if fullPattern != "" {
+		f, err := newPatternPIDFinder(fullPattern, true)
+		if err != nil {
+			return nil, err
+		}
+		finder = f
+		set++
+	}
+	if pidfile != "" {
+		finder = pidfilePIDFinder{path: pidfile}
+		set++
+	}
+	if user != "" {
+		finder = userPIDFinder{user: user}
+		set++
+	}
+	if pid > 0 {
+		set++
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("only one of -p, -pattern, -fullpattern, -pidfile, -user may be set")
+	}
+	if finder != nil {
+		return finder, nil
+	}
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid")
+	}
+	return staticPIDFinder{pids: []int32{int32(pid)}}, nil
+}
+
+// usingCgroup reports whether any of -cgroup/-cid/-cidfile select a cgroup
+// backend instead of the usual PID-based one.
+func usingCgroup() bool {
+	return cgroupPath != "" || cid != "" || cidfile != ""
+}
+
+// validateSelectionFlags enforces the mutual exclusivity -cgroup's flag help
+// already advertises: -cgroup/-cid/-cidfile pick a cgroup backend and can't
+// be combined with any of the PID-selection flags buildFinder resolves.
+func validateSelectionFlags() error {
+	if !usingCgroup() {
+		return nil
+	}
+	cgroupFlags := 0
+	for _, v := range []string{cgroupPath, cid, cidfile} {
+		if v != "" {
+			cgroupFlags++
+		}
+	}
+	if cgroupFlags > 1 {
+		return fmt.Errorf("only one of -cgroup, -cid, -cidfile may be set")
+	}
+	if pid > 0 || pattern != "" || fullPattern != "" || pidfile != "" || user != "" {
+		return fmt.Errorf("-cgroup/-cid/-cidfile are mutually exclusive with -p/-pattern/-fullpattern/-pidfile/-user")
+	}
+	return nil
+}
+
+// buildCgroupReporter resolves -cgroup/-cid/-cidfile into a CgroupReporter,
+// polling -cidfile for up to 30s if the container hasn't started yet.
+func buildCgroupReporter() (*CgroupReporter, error) {
+	path := cgroupPath
+	switch {
+	case path != "":
+	case cid != "":
+		resolved, err := resolveCgroupPath(cid)
+		if err != nil {
+			return nil, err
+		}
+		path = resolved
+	case cidfile != "":
+		resolved, err := waitForCgroup(func() (string, error) {
+			id, err := readCIDFile(cidfile)
+			if err != nil {
+				return "", err
+			}
+			return resolveCgroupPath(id)
+		}, 500*time.Millisecond, 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		path = resolved
+	}
+	return NewCgroupReporter(path)
+}
+
+// procSeries holds the samples collected for a single monitored PID. In
+// -tree mode Samples holds the whole-tree total and Children holds one
+// sub-series per direct child (for the stacked-area plot); in -threads
+// mode Threads holds a per-tick snapshot of per-TID CPU%.
+type procSeries struct {
+	Pid      int32
+	Name     string
+	Samples  []sample
+	Children map[int32]*procSeries
+	Threads  []threadSample
+	Maps     []mapSample
+}
+
+// ProcessInfo aggregates sampling results across every PID resolved by a
+// PIDFinder, so MemPlot/IoPlot/CpuPlot can render one series per process.
 type ProcessInfo struct {
-	Pid              int32
 	StartTime        time.Time
 	SamplingInterval time.Duration
-	Samples          []sample
+	Series           map[int32]*procSeries
 }
 
-func New(pid int32, duration, interval time.Duration) (*ProcessInfo, error) {
-	sampleNo := duration / interval
-	if sampleNo < 2 {
-		return nil, fmt.Errorf("need at least 2 samples, your sampling interval is too long or sampling duration is too short")
-	}
+// resolveInterval is how often a PIDFinder is re-queried while sampling,
+// so short-lived children spawned after the initial resolve are picked up.
+const resolveInterval = time.Second
 
-	proc, err := process.NewProcess(pid)
-	if err != nil {
-		return nil, err
+func New(finder PIDFinder, duration, interval time.Duration, tree, threads bool, memMetrics memMetricSet, mapsTopN int) (*ProcessInfo, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("sampling interval must be positive")
+	}
+	if duration != 0 && duration/interval < 2 {
+		return nil, fmt.Errorf("need at least 2 samples, your sampling interval is too long or sampling duration is too short")
 	}
 
 	start := time.Now()
-
 	pi := &ProcessInfo{
-		Pid:              pid,
 		StartTime:        start,
 		SamplingInterval: interval,
-		Samples:          make([]sample, 0, sampleNo),
+		Series:           make(map[int32]*procSeries),
 	}
 
-	running, err := proc.IsRunning()
-	if err != nil {
+	tracked := make(map[int32]*ProcessReporter)
+	if err := pi.resolve(finder, tracked, tree); err != nil {
 		return nil, err
 	}
+	lastResolve := start
+	threadTrackers := make(map[int32]*threadTracker)
 
-	// duration == 0 means sampling until pid exits
-	for t := time.Since(start); duration == 0 || t <= duration && running; t = time.Since(start) {
-		mem, err := proc.MemoryInfo()
-		if err != nil {
-			return nil, err
-		}
-		io, err := proc.IOCounters()
-		if err != nil {
-			return nil, err
-		}
-		c, err := proc.CPUPercent()
-		if err != nil {
-			return nil, err
+	// duration == 0 means sampling until every matched process exits. The
+	// loop keeps running on elapsed time alone; it only gives up once a
+	// resolve just ran and turned up zero running processes, so a pattern
+	// that hasn't matched anything yet (or whose only match exits the same
+	// tick a new one spawns) gets to wait for the next periodic resolve
+	// instead of exiting before it ever runs.
+	for t := time.Since(start); duration == 0 || t <= duration; t = time.Since(start) {
+		justResolved := false
+		if time.Since(lastResolve) >= resolveInterval {
+			if err := pi.resolve(finder, tracked, tree); err != nil {
+				return nil, err
+			}
+			lastResolve = time.Now()
+			justResolved = true
 		}
 
-		sample := sample{
-			mem:      mem,
-			io:       io,
-			cpu:      c,
-			interval: t,
+		anyRunning := false
+		for pid, reporter := range tracked {
+			running, err := reporter.Running()
+			if err != nil || !running {
+				continue
+			}
+			anyRunning = true
+
+			series := pi.Series[pid]
+			if tree {
+				total, childTotals, err := sampleTree(reporter.Proc, t)
+				if err != nil {
+					continue
+				}
+				if byPath := enrichTreeWithMemMetrics(&total, reporter.Proc, memMetrics, mapsTopN); byPath != nil {
+					series.Maps = append(series.Maps, mapSample{interval: t, byPath: byPath})
+				}
+				series.Samples = append(series.Samples, total)
+				if series.Children == nil {
+					series.Children = make(map[int32]*procSeries)
+				}
+				for childPid, childSample := range childTotals {
+					child, ok := series.Children[childPid]
+					if !ok {
+						childProc, err := process.NewProcess(childPid)
+						name := ""
+						if err == nil {
+							name, _ = childProc.Name()
+						}
+						child = &procSeries{Pid: childPid, Name: name}
+						series.Children[childPid] = child
+					}
+					child.Samples = append(child.Samples, childSample)
+				}
+			} else {
+				s, err := reporter.Sample(t)
+				if err != nil {
+					continue
+				}
+				if byPath := enrichWithMemMetrics(&s, reporter.Proc, memMetrics, mapsTopN); byPath != nil {
+					series.Maps = append(series.Maps, mapSample{interval: t, byPath: byPath})
+				}
+				series.Samples = append(series.Samples, s)
+			}
+
+			if threads {
+				tt, ok := threadTrackers[pid]
+				if !ok {
+					tt = newThreadTracker()
+					threadTrackers[pid] = tt
+				}
+				if ts, err := tt.sample(reporter.Proc, t); err == nil {
+					series.Threads = append(series.Threads, ts)
+				}
+			}
 		}
 
-		pi.Samples = append(pi.Samples, sample)
+		if justResolved && !anyRunning {
+			break
+		}
 		time.Sleep(interval)
-		running, err = proc.IsRunning()
+	}
+	if len(pi.Series) == 0 {
+		return nil, fmt.Errorf("no matching processes found")
+	}
+	return pi, nil
+}
+
+// resolve queries finder for the current set of matching PIDs and starts
+// tracking any that aren't already known.
+func (pi *ProcessInfo) resolve(finder PIDFinder, tracked map[int32]*ProcessReporter, tree bool) error {
+	pids, err := finder.Find()
+	if err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		if _, ok := tracked[pid]; ok {
+			continue
+		}
+		proc, err := process.NewProcess(pid)
 		if err != nil {
-			return nil, err
+			// Exited between being resolved and being inspected.
+			continue
 		}
+		name, _ := proc.Name()
+		tracked[pid] = &ProcessReporter{Proc: proc, Tree: tree}
+		pi.Series[pid] = &procSeries{Pid: pid, Name: name}
 	}
-	return pi, nil
+	return nil
 }
 
-func (pi *ProcessInfo) MemPlot() (*plot.Plot, error) {
+// seriesLabel returns the legend label for a monitored process, e.g.
+// "1234:sshd".
+func seriesLabel(s *procSeries) string {
+	if s.Name == "" {
+		return fmt.Sprintf("%d", s.Pid)
+	}
+	return fmt.Sprintf("%d:%s", s.Pid, s.Name)
+}
+
+// seriesColors cycles through a small fixed palette so each monitored
+// process gets a stable, distinguishable line color.
+var seriesColors = []color.RGBA{
+	{R: 255, G: 0, B: 0, A: 255},
+	{R: 0, G: 255, B: 0, A: 255},
+	{R: 0, G: 0, B: 255, A: 255},
+	{R: 255, G: 165, B: 0, A: 255},
+	{R: 160, G: 32, B: 240, A: 255},
+	{R: 0, G: 200, B: 200, A: 255},
+}
+
+func seriesColor(i int) color.RGBA {
+	return seriesColors[i%len(seriesColors)]
+}
+
+// sampleProcess takes a single snapshot of proc's memory/IO/CPU usage,
+// stamping it with t (the elapsed time since sampling started). It is
+// shared by the one-shot New() loop and the live -tui dashboard.
+func sampleProcess(proc *process.Process, t time.Duration) (sample, error) {
+	mem, err := proc.MemoryInfo()
+	if err != nil {
+		return sample{}, err
+	}
+	io, err := proc.IOCounters()
+	if err != nil {
+		return sample{}, err
+	}
+	c, err := proc.CPUPercent()
+	if err != nil {
+		return sample{}, err
+	}
+	return sample{
+		rss:        mem.RSS,
+		vms:        mem.VMS,
+		readCount:  io.ReadCount,
+		writeCount: io.WriteCount,
+		readBytes:  io.ReadBytes,
+		writeBytes: io.WriteBytes,
+		cpu:        c,
+		interval:   t,
+	}, nil
+}
+
+// sortedSeries returns pi.Series ordered by PID, so plots and legends are
+// stable across calls.
+func (pi *ProcessInfo) sortedSeries() []*procSeries {
+	series := make([]*procSeries, 0, len(pi.Series))
+	for _, s := range pi.Series {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Pid < series[j].Pid })
+	return series
+}
+
+// metricDashes gives each memory metric plotted for the same process a
+// distinct dash pattern, so e.g. RSS and PSS for pid 1234 stay tellable
+// apart even though they share a color. The five patterns repeat when more
+// than five metrics are selected.
+var metricDashes = [][]vg.Length{
+	nil,
+	{vg.Points(4), vg.Points(2)},
+	{vg.Points(1), vg.Points(2)},
+	{vg.Points(6), vg.Points(2), vg.Points(1), vg.Points(2)},
+	{vg.Points(2), vg.Points(2)},
+}
+
+// MemPlot renders one line per series per metric named in metrics (see
+// memMetricOrder for the supported set and their plotting order).
+func (pi *ProcessInfo) MemPlot(metrics memMetricSet) (*plot.Plot, error) {
 	pl, err := plot.New()
 	if err != nil {
 		return nil, err
 	}
-	pl.Title.Text = fmt.Sprintf("Memory Plot of PID %d", pi.Pid)
+	pl.Title.Text = "Memory Plot"
 	pl.X.Label.Text = "t (s)"
 	pl.Y.Label.Text = "KB"
 	pl.Add(plotter.NewGrid())
-	// RSS
-	ptsRss := make(plotter.XYs, len(pi.Samples))
-	// VMS
-	ptsVms := make(plotter.XYs, len(pi.Samples))
-	for i, s := range pi.Samples {
-		ptsRss[i].X = s.interval.Seconds()
-		ptsVms[i].X = s.interval.Seconds()
-		ptsRss[i].Y = float64(pi.Samples[i].mem.RSS) / 1024
-		ptsVms[i].Y = float64(pi.Samples[i].mem.VMS) / 1024
-	}
-	// RSS
-	lineRss, err := plotter.NewLine(ptsRss)
-	if err != nil {
-		return nil, err
+	for i, series := range pi.sortedSeries() {
+		dashIdx := 0
+		for _, key := range memMetricOrder {
+			if !metrics[key] {
+				continue
+			}
+			pts := make(plotter.XYs, len(series.Samples))
+			for j, s := range series.Samples {
+				pts[j].X = s.interval.Seconds()
+				pts[j].Y = memMetricValue(s, key)
+			}
+			line, err := plotter.NewLine(pts)
+			if err != nil {
+				return nil, err
+			}
+			line.LineStyle.Width = vg.Points(1)
+			line.LineStyle.Color = seriesColor(i)
+			line.LineStyle.Dashes = metricDashes[dashIdx%len(metricDashes)]
+			pl.Add(line)
+			pl.Legend.Add(seriesLabel(series)+" "+strings.ToUpper(key), line)
+			dashIdx++
+		}
 	}
-	lineRss.LineStyle.Width = vg.Points(1)
-	lineRss.LineStyle.Color = color.RGBA{R: 255, G: 0, B: 0, A: 255}
-	pl.Add(lineRss)
-	pl.Legend.Add("RSS", lineRss)
-	// VMS
-	lineVms, err := plotter.NewLine(ptsVms)
+	return pl, nil
+}
+
+// MapsPlot renders the N largest memory mappings of pid (captured per-tick
+// when -maps-top > 0), grouped by mapping path, as cumulative stacked lines
+// over time, the same technique ChildStackPlot uses for -tree (gonum/plot
+// has no native stacked-bar plotter).
+func (pi *ProcessInfo) MapsPlot(pid int32) (*plot.Plot, error) {
+	series, ok := pi.Series[pid]
+	if !ok {
+		return nil, fmt.Errorf("no series for pid %d", pid)
+	}
+	pl, err := plot.New()
 	if err != nil {
 		return nil, err
 	}
-	lineVms.LineStyle.Width = vg.Points(1)
-	lineVms.LineStyle.Color = color.RGBA{R: 0, G: 255, B: 0, A: 255}
-	pl.Add(lineVms)
-	pl.Legend.Add("VMS", lineVms)
+	pl.Title.Text = fmt.Sprintf("Top Memory Mappings of PID %d", pid)
+	pl.X.Label.Text = "t (s)"
+	pl.Y.Label.Text = "RSS KB (stacked)"
+	pl.Add(plotter.NewGrid())
+
+	paths := make(map[string]bool)
+	for _, ms := range series.Maps {
+		for p := range ms.byPath {
+			paths[p] = true
+		}
+	}
+	ordered := make([]string, 0, len(paths))
+	for p := range paths {
+		ordered = append(ordered, p)
+	}
+	sort.Strings(ordered)
+
+	cumulative := make([]float64, len(series.Maps))
+	for i, path := range ordered {
+		pts := make(plotter.XYs, len(series.Maps))
+		for j, ms := range series.Maps {
+			cumulative[j] += float64(ms.byPath[path]) / 1024
+			pts[j].X = ms.interval.Seconds()
+			pts[j].Y = cumulative[j]
+		}
+		line, err := plotter.NewLine(pts)
+		if err != nil {
+			return nil, err
+		}
+		line.LineStyle.Width = vg.Points(1)
+		line.LineStyle.Color = seriesColor(i)
+		pl.Add(line)
+		pl.Legend.Add(path, line)
+	}
 	return pl, nil
 }
 
@@ -155,64 +495,166 @@ func (pi *ProcessInfo) IoPlot() (*plot.Plot, error) {
 	if err != nil {
 		return nil, err
 	}
-	pl.Title.Text = fmt.Sprintf("IO Plot of PID %d", pi.Pid)
+	pl.Title.Text = "IO Plot"
 	pl.X.Label.Text = "t (s)"
 	pl.Y.Label.Text = "op"
 	pl.Add(plotter.NewGrid())
-	// read
-	ptsR := make(plotter.XYs, len(pi.Samples))
-	// write
-	ptsW := make(plotter.XYs, len(pi.Samples))
-	for i, s := range pi.Samples {
-		ptsR[i].X = s.interval.Seconds()
-		ptsW[i].X = s.interval.Seconds()
-		ptsR[i].Y = float64(pi.Samples[i].io.ReadCount)
-		ptsW[i].Y = float64(pi.Samples[i].io.WriteCount)
-	}
-	// read
-	lineR, err := plotter.NewLine(ptsR)
+	for i, series := range pi.sortedSeries() {
+		ptsR := make(plotter.XYs, len(series.Samples))
+		ptsW := make(plotter.XYs, len(series.Samples))
+		for j, s := range series.Samples {
+			ptsR[j].X = s.interval.Seconds()
+			ptsW[j].X = s.interval.Seconds()
+			ptsR[j].Y = float64(s.readCount)
+			ptsW[j].Y = float64(s.writeCount)
+		}
+		lineR, err := plotter.NewLine(ptsR)
+		if err != nil {
+			return nil, err
+		}
+		lineR.LineStyle.Width = vg.Points(1)
+		lineR.LineStyle.Color = seriesColor(i)
+		pl.Add(lineR)
+		pl.Legend.Add(seriesLabel(series)+" IO Read", lineR)
+
+		lineW, err := plotter.NewLine(ptsW)
+		if err != nil {
+			return nil, err
+		}
+		lineW.LineStyle.Width = vg.Points(1)
+		lineW.LineStyle.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+		lineW.LineStyle.Color = seriesColor(i)
+		pl.Add(lineW)
+		pl.Legend.Add(seriesLabel(series)+" IO Write", lineW)
+	}
+	return pl, nil
+}
+
+func (pi *ProcessInfo) CpuPlot() (*plot.Plot, error) {
+	pl, err := plot.New()
 	if err != nil {
 		return nil, err
 	}
-	lineR.LineStyle.Width = vg.Points(1)
-	lineR.LineStyle.Color = color.RGBA{R: 255, G: 0, B: 0, A: 255}
-	pl.Add(lineR)
-	pl.Legend.Add("IO Read", lineR)
-	// write
-	lineW, err := plotter.NewLine(ptsR)
+	pl.Title.Text = "CPU Plot"
+	pl.X.Label.Text = "t (s)"
+	pl.Y.Label.Text = "%"
+	pl.Add(plotter.NewGrid())
+	for i, series := range pi.sortedSeries() {
+		pts := make(plotter.XYs, len(series.Samples))
+		for j, s := range series.Samples {
+			pts[j].X = s.interval.Seconds()
+			pts[j].Y = s.cpu * 100
+		}
+		line, err := plotter.NewLine(pts)
+		if err != nil {
+			return nil, err
+		}
+		line.LineStyle.Width = vg.Points(1)
+		line.LineStyle.Color = seriesColor(i)
+		pl.Add(line)
+		pl.Legend.Add(seriesLabel(series)+" CPU", line)
+	}
+	return pl, nil
+}
+
+// ChildStackPlot renders a stacked-area plot of RSS contributed by each
+// direct child (and its own descendants) of the -tree monitored root pid,
+// so it's visible which child is responsible for the tree's memory growth.
+func (pi *ProcessInfo) ChildStackPlot(root int32) (*plot.Plot, error) {
+	series, ok := pi.Series[root]
+	if !ok {
+		return nil, fmt.Errorf("no series for pid %d", root)
+	}
+	pl, err := plot.New()
 	if err != nil {
 		return nil, err
 	}
-	lineW.LineStyle.Width = vg.Points(1)
-	lineW.LineStyle.Color = color.RGBA{R: 0, G: 255, B: 0, A: 255}
-	pl.Add(lineW)
-	pl.Legend.Add("IO Write", lineW)
+	pl.Title.Text = fmt.Sprintf("Child RSS Breakdown of PID %d", root)
+	pl.X.Label.Text = "t (s)"
+	pl.Y.Label.Text = "KB (stacked)"
+	pl.Add(plotter.NewGrid())
+
+	children := make([]*procSeries, 0, len(series.Children))
+	for _, c := range series.Children {
+		children = append(children, c)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Pid < children[j].Pid })
+
+	// tickIndex maps each elapsed-time tick to its position in the root's
+	// own sample sequence, so a child that joins partway through (the
+	// normal case for a short-lived child) gets stacked at the tick it
+	// actually appeared at rather than at its own sample slice's index 0.
+	n := len(series.Samples)
+	tickIndex := make(map[time.Duration]int, n)
+	for j, s := range series.Samples {
+		tickIndex[s.interval] = j
+	}
+
+	cumulative := make([]float64, n)
+	for i, child := range children {
+		values := make([]float64, n)
+		for _, s := range child.Samples {
+			if j, ok := tickIndex[s.interval]; ok {
+				values[j] = float64(s.rss) / 1024
+			}
+		}
+
+		pts := make(plotter.XYs, n)
+		for j := 0; j < n; j++ {
+			cumulative[j] += values[j]
+			pts[j].X = series.Samples[j].interval.Seconds()
+			pts[j].Y = cumulative[j]
+		}
+		line, err := plotter.NewLine(pts)
+		if err != nil {
+			return nil, err
+		}
+		line.LineStyle.Width = vg.Points(1)
+		line.LineStyle.Color = seriesColor(i)
+		pl.Add(line)
+		pl.Legend.Add(seriesLabel(child), line)
+	}
 	return pl, nil
 }
 
-func (pi *ProcessInfo) CpuPlot() (*plot.Plot, error) {
+// ThreadPlot graphs per-thread CPU% for a monitored process, one line per
+// TID, using the per-tick snapshots collected when -threads is set.
+func (pi *ProcessInfo) ThreadPlot(pid int32) (*plot.Plot, error) {
+	series, ok := pi.Series[pid]
+	if !ok {
+		return nil, fmt.Errorf("no series for pid %d", pid)
+	}
 	pl, err := plot.New()
 	if err != nil {
 		return nil, err
 	}
-	pl.Title.Text = fmt.Sprintf("CPU Plot of PID %d", pi.Pid)
+	pl.Title.Text = fmt.Sprintf("Per-Thread CPU Plot of PID %d", pid)
 	pl.X.Label.Text = "t (s)"
 	pl.Y.Label.Text = "%"
 	pl.Add(plotter.NewGrid())
-	pts := make(plotter.XYs, len(pi.Samples))
-	for i, s := range pi.Samples {
-		pts[i].X = s.interval.Seconds()
-		pts[i].Y = pi.Samples[i].cpu * 100
+
+	byTid := make(map[int32]plotter.XYs)
+	for _, ts := range series.Threads {
+		for tid, pct := range ts.percents {
+			byTid[tid] = append(byTid[tid], plotter.XY{X: ts.interval.Seconds(), Y: pct})
+		}
 	}
-	line, err := plotter.NewLine(pts)
-	if err != nil {
-		return nil, err
+	tids := make([]int32, 0, len(byTid))
+	for tid := range byTid {
+		tids = append(tids, tid)
 	}
-	line.LineStyle.Width = vg.Points(1)
-	line.LineStyle.Color = color.RGBA{R: 0, G: 0, B: 0, A: 255}
-	pl.Add(line)
-	pl.Legend.Add("CPU", line)
+	sort.Slice(tids, func(i, j int) bool { return tids[i] < tids[j] })
 
+	for i, tid := range tids {
+		line, err := plotter.NewLine(byTid[tid])
+		if err != nil {
+			return nil, err
+		}
+		line.LineStyle.Width = vg.Points(1)
+		line.LineStyle.Color = seriesColor(i)
+		pl.Add(line)
+		pl.Legend.Add(fmt.Sprintf("tid %d", tid), line)
+	}
 	return pl, nil
 }
 
@@ -248,11 +690,29 @@ func (pi *ProcessInfo) Save(plots [][]*plot.Plot, width, height vg.Length, filen
 	return nil
 }
 
+// sample is a single tick of resource usage, generic over Reporter
+// backends: both the gopsutil-backed ProcessReporter and the
+// /sys/fs/cgroup-backed CgroupReporter fill it out the same way.
 type sample struct {
-	mem      *process.MemoryInfoStat
-	io       *process.IOCountersStat
-	cpu      float64
-	interval time.Duration
+	rss        uint64
+	vms        uint64
+	readCount  uint64
+	writeCount uint64
+	readBytes  uint64
+	writeBytes uint64
+	cpu        float64
+	interval   time.Duration
+
+	// Richer memory metrics, filled in by enrichWithMemMetrics: shared/text/
+	// data/dirty together when -mem-metrics selects any of them, pss/uss/swap
+	// together when it selects any of those or -maps-top is set; zero otherwise.
+	shared uint64
+	text   uint64
+	data   uint64
+	dirty  uint64
+	swap   uint64
+	pss    uint64
+	uss    uint64
 }
 
 func check(err error) {
@@ -267,32 +727,97 @@ func main() {
 
 	if help {
 		flag.Usage()
-	} else if version {
+		return
+	}
+	if version {
 		fmt.Println("version: 0.0.1")
-	} else if pid <= 0 {
-		fmt.Println("invalid pid")
-	} else {
-		imgWidth, err := vg.ParseLength(width)
+		return
+	}
+
+	imgWidth, err := vg.ParseLength(width)
+	check(err)
+	imgHeight, err := vg.ParseLength(height)
+	check(err)
+
+	check(validateSelectionFlags())
+
+	if tui {
+		finder, err := buildFinder()
+		check(err)
+		check(runDashboard(finder, interval, imgWidth, imgHeight, output))
+		return
+	}
+
+	memMetricSelection, err := parseMemMetrics(memMetrics)
+	check(err)
+
+	var pi *ProcessInfo
+	if usingCgroup() {
+		reporter, err := buildCgroupReporter()
 		check(err)
-		imgHeight, err := vg.ParseLength(height)
+		fmt.Println("Collecting info from cgroup:", reporter.Label())
+		if duration == 0 {
+			fmt.Println("Your sampling duration is 0, which means sample until the cgroup disappears")
+		}
+		pi, err = NewFromReporter(reporter, duration, interval)
+		check(err)
+	} else {
+		finder, err := buildFinder()
 		check(err)
-		fmt.Println("Collecting info from pid:", pid)
+
+		fmt.Println("Collecting info...")
 		if duration == 0 {
-			fmt.Println("Your sampling duration is 0, which means sample pid until it exits")
+			fmt.Println("Your sampling duration is 0, which means sample until all matched processes exit")
 		}
-		pi, err := New(int32(pid), duration, interval)
+		pi, err = New(finder, duration, interval, tree, threads, memMetricSelection, mapsTop)
 		check(err)
-		memPlot, err := pi.MemPlot()
+	}
+	if formats.has("png") {
+		memPlot, err := pi.MemPlot(memMetricSelection)
 		check(err)
 		ioPlot, err := pi.IoPlot()
 		check(err)
 		cpuPlot, err := pi.CpuPlot()
 		check(err)
-		plots := make([][]*plot.Plot, 3)
-		plots[0] = []*plot.Plot{memPlot}
-		plots[1] = []*plot.Plot{ioPlot}
-		plots[2] = []*plot.Plot{cpuPlot}
-		err = pi.Save(plots, imgWidth, imgHeight, output)
-		check(err)
+		plots := [][]*plot.Plot{{memPlot}, {ioPlot}, {cpuPlot}}
+
+		if tree {
+			for _, series := range pi.sortedSeries() {
+				if len(series.Children) == 0 {
+					continue
+				}
+				stackPlot, err := pi.ChildStackPlot(series.Pid)
+				check(err)
+				plots = append(plots, []*plot.Plot{stackPlot})
+			}
+		}
+		if threads {
+			for _, series := range pi.sortedSeries() {
+				threadPlot, err := pi.ThreadPlot(series.Pid)
+				check(err)
+				plots = append(plots, []*plot.Plot{threadPlot})
+			}
+		}
+		if mapsTop > 0 {
+			for _, series := range pi.sortedSeries() {
+				if len(series.Maps) == 0 {
+					continue
+				}
+				mapsPlot, err := pi.MapsPlot(series.Pid)
+				check(err)
+				plots = append(plots, []*plot.Plot{mapsPlot})
+			}
+		}
+
+		check(pi.Save(plots, imgWidth, imgHeight, output))
+	}
+	if formats.has("csv") {
+		check(pi.WriteCSV(formatPath(output, "csv")))
+	}
+	if formats.has("json") {
+		check(pi.WriteJSON(formatPath(output, "json")))
+	}
+	if formats.has("influx") {
+		check(pi.WriteInflux(formatPath(output, "influx")))
 	}
 }
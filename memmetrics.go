@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// memMetricSet is the set of memory metrics -mem-metrics selected for
+// MemPlot, parsed from its comma-separated value.
+type memMetricSet map[string]bool
+
+// memMetricOrder fixes the line/legend order for MemPlot regardless of the
+// order -mem-metrics was written in.
+var memMetricOrder = []string{"rss", "vms", "pss", "uss", "swap", "shared", "text", "data", "dirty"}
+
+func parseMemMetrics(spec string) (memMetricSet, error) {
+	set := make(memMetricSet)
+	for _, m := range strings.Split(spec, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		found := false
+		for _, known := range memMetricOrder {
+			if m == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown memory metric %q (want one of %s)", m, strings.Join(memMetricOrder, ", "))
+		}
+		set[m] = true
+	}
+	return set, nil
+}
+
+// wantsMaps reports whether any selected metric requires walking
+// /proc/<pid>/maps (PSS/USS/Swap are only available that way).
+func (set memMetricSet) wantsMaps() bool {
+	return set["pss"] || set["uss"] || set["swap"]
+}
+
+// wantsScalarSum reports whether any selected metric is one enrichTreeWithMemMetrics
+// needs to sum across a -tree root's descendants (everything beyond plain
+// RSS/VMS, which sampleTree already sums).
+func (set memMetricSet) wantsScalarSum() bool {
+	return set["shared"] || set["text"] || set["data"] || set["dirty"] || set.wantsMaps()
+}
+
+func memMetricValue(s sample, key string) float64 {
+	switch key {
+	case "rss":
+		return float64(s.rss) / 1024
+	case "vms":
+		return float64(s.vms) / 1024
+	case "pss":
+		return float64(s.pss) / 1024
+	case "uss":
+		return float64(s.uss) / 1024
+	case "swap":
+		return float64(s.swap) / 1024
+	case "shared":
+		return float64(s.shared) / 1024
+	case "text":
+		return float64(s.text) / 1024
+	case "data":
+		return float64(s.data) / 1024
+	case "dirty":
+		return float64(s.dirty) / 1024
+	default:
+		return 0
+	}
+}
+
+// mapSample is one tick of per-mapping RSS, keyed by mapping path, for the
+// mapsTopN largest mappings captured that tick.
+type mapSample struct {
+	interval time.Duration
+	byPath   map[string]uint64
+}
+
+// enrichWithMemMetrics fills in s's richer memory fields (beyond RSS/VMS)
+// from proc: shared/text/data/dirty when -mem-metrics selects any of them,
+// pss/uss/swap when it selects any of those or mapsTopN > 0. It returns the
+// mapsTopN largest mappings by RSS for MapsPlot (nil if mapsTopN is 0 or the
+// mappings cannot be read).
+func enrichWithMemMetrics(s *sample, proc *process.Process, metrics memMetricSet, mapsTopN int) map[string]uint64 {
+	if metrics["shared"] || metrics["text"] || metrics["data"] || metrics["dirty"] {
+		if ex, err := proc.MemoryInfoEx(); err == nil {
+			s.shared = ex.Shared
+			s.text = ex.Text
+			s.data = ex.Data
+			s.dirty = ex.Dirty
+		}
+	}
+
+	if !metrics.wantsMaps() && mapsTopN == 0 {
+		return nil
+	}
+	maps, err := proc.MemoryMaps(true)
+	if err != nil || maps == nil {
+		return nil
+	}
+
+	var pss, uss, swap uint64
+	byPath := make(map[string]uint64, len(*maps))
+	for _, m := range *maps {
+		pss += m.Pss
+		swap += m.Swap
+		uss += m.PrivateClean + m.PrivateDirty
+		byPath[m.Path] += m.Rss
+	}
+	s.pss = pss
+	s.uss = uss
+	s.swap = swap
+
+	if mapsTopN <= 0 {
+		return nil
+	}
+	return topNMappings(byPath, mapsTopN)
+}
+
+// enrichTreeWithMemMetrics is enrichWithMemMetrics's -tree counterpart: it
+// folds in root's own richer memory metrics exactly like enrichWithMemMetrics,
+// then sums the scalar ones (everything but the per-mapping breakdown) across
+// every descendant too, the same way sampleTree already sums RSS/VMS/IO/CPU,
+// so total really is "the whole-tree total" its procSeries doc comment
+// promises. The per-mapping breakdown stays scoped to root: summing mapped
+// file paths across unrelated processes wouldn't produce a meaningful series.
+func enrichTreeWithMemMetrics(total *sample, root *process.Process, metrics memMetricSet, mapsTopN int) map[string]uint64 {
+	byPath := enrichWithMemMetrics(total, root, metrics, mapsTopN)
+	if !metrics.wantsScalarSum() {
+		return byPath
+	}
+
+	var walk func(p *process.Process)
+	walk = func(p *process.Process) {
+		kids, err := p.Children()
+		if err != nil {
+			return
+		}
+		for _, kid := range kids {
+			var s sample
+			enrichWithMemMetrics(&s, kid, metrics, 0)
+			total.shared += s.shared
+			total.text += s.text
+			total.data += s.data
+			total.dirty += s.dirty
+			total.pss += s.pss
+			total.uss += s.uss
+			total.swap += s.swap
+			walk(kid)
+		}
+	}
+	walk(root)
+	return byPath
+}
+
+// topNMappings keeps the n largest entries of byPath (by RSS), so MapsPlot
+// doesn't grow one series per shared library the process has mapped.
+func topNMappings(byPath map[string]uint64, n int) map[string]uint64 {
+	type kv struct {
+		path string
+		rss  uint64
+	}
+	all := make([]kv, 0, len(byPath))
+	for p, rss := range byPath {
+		all = append(all, kv{p, rss})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].rss > all[j].rss })
+	if len(all) > n {
+		all = all[:n]
+	}
+	top := make(map[string]uint64, len(all))
+	for _, e := range all {
+		top[e.path] = e.rss
+	}
+	return top
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatFlag implements flag.Value for a repeatable -format flag, e.g.
+// -format csv -format json. It defaults to {"png"}; the first explicit
+// -format clears that default instead of appending to it.
+type formatFlag struct {
+	values  []string
+	touched bool
+}
+
+func (f *formatFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.values, ",")
+}
+
+func (f *formatFlag) Set(v string) error {
+	switch v {
+	case "png", "csv", "json", "influx":
+	default:
+		return fmt.Errorf("unknown -format %q (want png, csv, json, or influx)", v)
+	}
+	if !f.touched {
+		f.values = nil
+		f.touched = true
+	}
+	f.values = append(f.values, v)
+	return nil
+}
+
+func (f *formatFlag) has(name string) bool {
+	for _, v := range f.values {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleRecord is the JSON/CSV/line-protocol representation of one sample:
+// an absolute timestamp plus every field sample carries. The richer memory
+// fields are zero unless -mem-metrics (or, for pss/uss/swap, -maps-top)
+// caused them to be sampled (see enrichWithMemMetrics); they're included
+// unconditionally here so none of them are silently dropped from the
+// non-PNG output formats.
+type sampleRecord struct {
+	Pid        int32     `json:"pid"`
+	Name       string    `json:"name,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Elapsed    float64   `json:"elapsed_seconds"`
+	RSS        uint64    `json:"rss"`
+	VMS        uint64    `json:"vms"`
+	PSS        uint64    `json:"pss"`
+	USS        uint64    `json:"uss"`
+	Swap       uint64    `json:"swap"`
+	Shared     uint64    `json:"shared"`
+	Text       uint64    `json:"text"`
+	Data       uint64    `json:"data"`
+	Dirty      uint64    `json:"dirty"`
+	ReadCount  uint64    `json:"read_count"`
+	WriteCount uint64    `json:"write_count"`
+	ReadBytes  uint64    `json:"read_bytes"`
+	WriteBytes uint64    `json:"write_bytes"`
+	CPUPercent float64   `json:"cpu_percent"`
+}
+
+// records flattens every series in pi into a stable, pid-then-time-ordered
+// slice of sampleRecords, shared by the CSV/JSON/influx writers.
+func (pi *ProcessInfo) records() []sampleRecord {
+	var recs []sampleRecord
+	for _, series := range pi.sortedSeries() {
+		for _, s := range series.Samples {
+			recs = append(recs, sampleRecord{
+				Pid:        series.Pid,
+				Name:       series.Name,
+				Timestamp:  pi.StartTime.Add(s.interval),
+				Elapsed:    s.interval.Seconds(),
+				RSS:        s.rss,
+				VMS:        s.vms,
+				PSS:        s.pss,
+				USS:        s.uss,
+				Swap:       s.swap,
+				Shared:     s.shared,
+				Text:       s.text,
+				Data:       s.data,
+				Dirty:      s.dirty,
+				ReadCount:  s.readCount,
+				WriteCount: s.writeCount,
+				ReadBytes:  s.readBytes,
+				WriteBytes: s.writeBytes,
+				CPUPercent: s.cpu * 100,
+			})
+		}
+	}
+	return recs
+}
+
+// formatPath swaps filename's extension for ext, e.g. "pinfo.png" + "json"
+// -> "pinfo.json".
+func formatPath(filename, ext string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + "." + ext
+}
+
+// WriteJSON dumps every sample in pi, across all series, as a JSON array.
+func (pi *ProcessInfo) WriteJSON(filename string) error {
+	data, err := json.MarshalIndent(pi.records(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// WriteCSV dumps every sample in pi as one row, in a stable column order.
+func (pi *ProcessInfo) WriteCSV(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+
+	header := []string{"pid", "name", "timestamp", "elapsed_seconds", "rss", "vms", "pss", "uss", "swap", "shared", "text", "data", "dirty", "read_count", "write_count", "read_bytes", "write_bytes", "cpu_percent"}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, r := range pi.records() {
+		row := []string{
+			strconv.Itoa(int(r.Pid)),
+			r.Name,
+			r.Timestamp.Format(time.RFC3339Nano),
+			strconv.FormatFloat(r.Elapsed, 'f', -1, 64),
+			strconv.FormatUint(r.RSS, 10),
+			strconv.FormatUint(r.VMS, 10),
+			strconv.FormatUint(r.PSS, 10),
+			strconv.FormatUint(r.USS, 10),
+			strconv.FormatUint(r.Swap, 10),
+			strconv.FormatUint(r.Shared, 10),
+			strconv.FormatUint(r.Text, 10),
+			strconv.FormatUint(r.Data, 10),
+			strconv.FormatUint(r.Dirty, 10),
+			strconv.FormatUint(r.ReadCount, 10),
+			strconv.FormatUint(r.WriteCount, 10),
+			strconv.FormatUint(r.ReadBytes, 10),
+			strconv.FormatUint(r.WriteBytes, 10),
+			strconv.FormatFloat(r.CPUPercent, 'f', -1, 64),
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return w.Error()
+}
+
+// WriteInflux dumps every sample in pi as InfluxDB line protocol, one
+// "pinfo" measurement per sample, so it can be piped into telegraf or an
+// InfluxDB line-protocol write endpoint.
+func (pi *ProcessInfo) WriteInflux(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	for _, r := range pi.records() {
+		fmt.Fprintf(w, "pinfo,pid=%d rss=%d,vms=%d,pss=%d,uss=%d,swap=%d,shared=%d,text=%d,data=%d,dirty=%d,cpu=%f,read_count=%d,write_count=%d,read_bytes=%d,write_bytes=%d %d\n",
+			r.Pid, r.RSS, r.VMS, r.PSS, r.USS, r.Swap, r.Shared, r.Text, r.Data, r.Dirty, r.CPUPercent, r.ReadCount, r.WriteCount, r.ReadBytes, r.WriteBytes, r.Timestamp.UnixNano())
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// PIDFinder resolves the set of PIDs to monitor. It mirrors telegraf's
+// procstat plugin: a single -p flag is one of several ways to select
+// processes, alongside matching by command name/line, a pidfile, or an
+// owning user. Find is called once up front and then periodically during
+// sampling so short-lived children are picked up.
+type PIDFinder interface {
+	Find() ([]int32, error)
+}
+
+// staticPIDFinder always returns the same fixed set of PIDs. It backs the
+// plain -p flag.
+type staticPIDFinder struct {
+	pids []int32
+}
+
+func (f staticPIDFinder) Find() ([]int32, error) {
+	return f.pids, nil
+}
+
+// patternPIDFinder matches processes whose name (or, with full set, whose
+// full command line) matches a regular expression, similar to `pgrep` /
+// `pgrep -f`.
+type patternPIDFinder struct {
+	re   *regexp.Regexp
+	full bool
+}
+
+func newPatternPIDFinder(pattern string, full bool) (*patternPIDFinder, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return &patternPIDFinder{re: re, full: full}, nil
+}
+
+func (f *patternPIDFinder) Find() ([]int32, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	var pids []int32
+	for _, proc := range procs {
+		var subject string
+		var err error
+		if f.full {
+			subject, err = proc.Cmdline()
+		} else {
+			subject, err = proc.Name()
+		}
+		if err != nil {
+			// Process may have exited between listing and inspection.
+			continue
+		}
+		if f.re.MatchString(subject) {
+			pids = append(pids, proc.Pid)
+		}
+	}
+	return pids, nil
+}
+
+// pidfilePIDFinder reads a single PID from a file, re-reading it on every
+// Find() call so it picks up a PID written by a restarted supervisor.
+type pidfilePIDFinder struct {
+	path string
+}
+
+func (f pidfilePIDFinder) Find() ([]int32, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(bytes.TrimSpace(data))), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("pidfile %s: %w", f.path, err)
+	}
+	return []int32{int32(pid)}, nil
+}
+
+// userPIDFinder matches all processes owned by a given username.
+type userPIDFinder struct {
+	user string
+}
+
+func (f userPIDFinder) Find() ([]int32, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	var pids []int32
+	for _, proc := range procs {
+		username, err := proc.Username()
+		if err != nil {
+			continue
+		}
+		if username == f.user {
+			pids = append(pids, proc.Pid)
+		}
+	}
+	return pids, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// Reporter produces a stream of resource-usage samples for whatever it is
+// monitoring — a PID (optionally with its child tree), a cgroup, or any
+// future backend. ProcessInfo drives every Reporter the same way, so
+// MemPlot/IoPlot/CpuPlot need no knowledge of where the numbers came from.
+type Reporter interface {
+	// Sample takes one snapshot, stamped with t (elapsed time since
+	// sampling started).
+	Sample(t time.Duration) (sample, error)
+	// Running reports whether there is still something to sample.
+	Running() (bool, error)
+	// Label is the legend/series name for this reporter.
+	Label() string
+}
+
+// ProcessReporter is the original gopsutil-backed Reporter for a single
+// PID, optionally folding in its child tree the way -tree mode does.
+type ProcessReporter struct {
+	Proc *process.Process
+	Tree bool
+}
+
+func (r *ProcessReporter) Sample(t time.Duration) (sample, error) {
+	if r.Tree {
+		total, _, err := sampleTree(r.Proc, t)
+		return total, err
+	}
+	return sampleProcess(r.Proc, t)
+}
+
+func (r *ProcessReporter) Running() (bool, error) {
+	return r.Proc.IsRunning()
+}
+
+func (r *ProcessReporter) Label() string {
+	name, _ := r.Proc.Name()
+	return name
+}
+
+// NewFromReporter drives a single Reporter on the same sampling cadence as
+// New(), for backends that aren't resolved via a PIDFinder (e.g. a
+// CgroupReporter monitoring a container as a whole).
+func NewFromReporter(reporter Reporter, duration, interval time.Duration) (*ProcessInfo, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("sampling interval must be positive")
+	}
+	if duration != 0 && duration/interval < 2 {
+		return nil, fmt.Errorf("need at least 2 samples, your sampling interval is too long or sampling duration is too short")
+	}
+
+	start := time.Now()
+	series := &procSeries{Name: reporter.Label()}
+	pi := &ProcessInfo{
+		StartTime:        start,
+		SamplingInterval: interval,
+		Series:           map[int32]*procSeries{0: series},
+	}
+
+	running, err := reporter.Running()
+	if err != nil {
+		return nil, err
+	}
+	for t := time.Since(start); (duration == 0 || t <= duration) && running; t = time.Since(start) {
+		if s, err := reporter.Sample(t); err == nil {
+			series.Samples = append(series.Samples, s)
+		}
+		time.Sleep(interval)
+		running, err = reporter.Running()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pi, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// sampleTree snapshots root plus every descendant it has spawned. total is
+// the whole-tree sum (what -tree plots as the aggregated series); children
+// holds one summed sample per direct child, with that child's own
+// descendants folded in, for the per-child stacked-area plot.
+func sampleTree(root *process.Process, t time.Duration) (total sample, children map[int32]sample, err error) {
+	total, err = sampleProcess(root, t)
+	if err != nil {
+		return sample{}, nil, err
+	}
+
+	kids, err := root.Children()
+	if err != nil {
+		// gopsutil returns an error when a process has no children.
+		return total, nil, nil
+	}
+
+	children = make(map[int32]sample, len(kids))
+	for _, child := range kids {
+		childTotal, err := sampleSubtree(child, t)
+		if err != nil {
+			continue
+		}
+		children[child.Pid] = childTotal
+		total = addSamples(total, childTotal)
+	}
+	return total, children, nil
+}
+
+// sampleSubtree sums proc and all of its descendants into a single sample,
+// so a child that has itself forked children still counts as one bucket
+// in the stacked-area plot.
+func sampleSubtree(proc *process.Process, t time.Duration) (sample, error) {
+	s, err := sampleProcess(proc, t)
+	if err != nil {
+		return sample{}, err
+	}
+	grandchildren, err := proc.Children()
+	if err != nil {
+		return s, nil
+	}
+	for _, gc := range grandchildren {
+		gs, err := sampleSubtree(gc, t)
+		if err != nil {
+			continue
+		}
+		s = addSamples(s, gs)
+	}
+	return s, nil
+}
+
+// addSamples sums two samples' memory/IO/CPU figures, keeping a's
+// timestamp.
+func addSamples(a, b sample) sample {
+	return sample{
+		rss:        a.rss + b.rss,
+		vms:        a.vms + b.vms,
+		readCount:  a.readCount + b.readCount,
+		writeCount: a.writeCount + b.writeCount,
+		readBytes:  a.readBytes + b.readBytes,
+		writeBytes: a.writeBytes + b.writeBytes,
+		cpu:        a.cpu + b.cpu,
+		interval:   a.interval,
+	}
+}
+
+// threadSample is one tick of per-thread CPU% for a process, keyed by TID.
+type threadSample struct {
+	interval time.Duration
+	percents map[int32]float64
+}
+
+// threadTracker turns gopsutil's cumulative per-thread CPU times into a
+// per-tick CPU%, the same way process.Process.CPUPercent() does for the
+// whole process.
+type threadTracker struct {
+	prevTimes map[int32]float64
+	prevAt    time.Time
+}
+
+func newThreadTracker() *threadTracker {
+	return &threadTracker{prevTimes: make(map[int32]float64)}
+}
+
+func (tt *threadTracker) sample(proc *process.Process, t time.Duration) (threadSample, error) {
+	threads, err := proc.Threads()
+	if err != nil {
+		return threadSample{}, err
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(tt.prevAt).Seconds()
+	percents := make(map[int32]float64, len(threads))
+	for tid, times := range threads {
+		total := times.Total()
+		if prev, ok := tt.prevTimes[tid]; ok && elapsed > 0 {
+			percents[tid] = (total - prev) / elapsed * 100
+		}
+		tt.prevTimes[tid] = total
+	}
+	tt.prevAt = now
+
+	return threadSample{interval: t, percents: percents}, nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shirou/gopsutil/process"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+)
+
+// dashboard is the live view backing -tui mode. It keeps sampling every PID
+// finder resolves in the background and redraws on every tick, instead of
+// writing a single PNG once the sampling duration elapses. Each monitored
+// PID gets its own tab; Tab/Shift+Tab cycles between them.
+type dashboard struct {
+	app    *tview.Application
+	tabs   *tview.Pages
+	status *tview.TextView
+
+	mu       sync.Mutex
+	pi       *ProcessInfo
+	procs    map[int32]*process.Process
+	views    map[int32]*tview.TextView
+	order    []int32
+	active   int
+	paused   bool
+	interval time.Duration
+
+	finder              PIDFinder
+	imgWidth, imgHeight vg.Length
+	output              string
+}
+
+// runDashboard launches the interactive terminal UI and blocks until the
+// user quits. finder is re-queried every resolveInterval, the same as the
+// non-interactive New() loop, so newly matched processes gain their own tab
+// while the dashboard is running. It reuses MemPlot/IoPlot/CpuPlot to export
+// the currently buffered samples of every tracked PID to a single PNG on
+// demand.
+func runDashboard(finder PIDFinder, interval time.Duration, imgWidth, imgHeight vg.Length, output string) error {
+	d := &dashboard{
+		app:      tview.NewApplication(),
+		tabs:     tview.NewPages(),
+		status:   tview.NewTextView().SetDynamicColors(true),
+		procs:    make(map[int32]*process.Process),
+		views:    make(map[int32]*tview.TextView),
+		interval: interval,
+		pi: &ProcessInfo{
+			StartTime:        time.Now(),
+			SamplingInterval: interval,
+			Series:           make(map[int32]*procSeries),
+		},
+		finder:    finder,
+		imgWidth:  imgWidth,
+		imgHeight: imgHeight,
+		output:    output,
+	}
+
+	if err := d.resolve(); err != nil {
+		return err
+	}
+	if len(d.order) == 0 {
+		return fmt.Errorf("no matching processes found")
+	}
+
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(d.tabs, 0, 1, false).
+		AddItem(d.status, 1, 0, false)
+	d.setStatus()
+
+	d.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTab:
+			d.switchTab(1)
+			return nil
+		case tcell.KeyBacktab:
+			d.switchTab(-1)
+			return nil
+		}
+		switch event.Rune() {
+		case 'q':
+			d.app.Stop()
+			return nil
+		case 'p':
+			d.mu.Lock()
+			d.paused = !d.paused
+			d.mu.Unlock()
+			d.setStatus()
+		case '+':
+			d.adjustInterval(10 * time.Millisecond)
+		case '-':
+			d.adjustInterval(-10 * time.Millisecond)
+		case 'e':
+			if err := d.export(); err != nil {
+				d.status.SetText(fmt.Sprintf("[red]export failed: %v", err))
+			}
+		}
+		return event
+	})
+
+	go d.sampleLoop()
+
+	return d.app.SetRoot(root, true).Run()
+}
+
+// resolve queries d.finder for the current set of matching PIDs and gives
+// each newly matched one its own tab, mirroring ProcessInfo.resolve.
+func (d *dashboard) resolve() error {
+	pids, err := d.finder.Find()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, pid := range pids {
+		if _, ok := d.procs[pid]; ok {
+			continue
+		}
+		proc, err := process.NewProcess(pid)
+		if err != nil {
+			// Exited between being resolved and being inspected.
+			continue
+		}
+		name, _ := proc.Name()
+
+		view := tview.NewTextView().SetDynamicColors(true).SetChangedFunc(func() { d.app.Draw() })
+		d.procs[pid] = proc
+		d.views[pid] = view
+		d.pi.Series[pid] = &procSeries{Pid: pid, Name: name}
+		d.tabs.AddPage(seriesTabName(pid), view, true, len(d.order) == 0)
+		d.order = append(d.order, pid)
+	}
+	return nil
+}
+
+func seriesTabName(pid int32) string {
+	return fmt.Sprintf("pid-%d", pid)
+}
+
+// sampleLoop repeatedly snapshots every tracked process and redraws its tab
+// until the application is stopped. It honors pause/resume and the interval
+// adjusted at runtime via the + / - hotkeys, and re-resolves d.finder on the
+// same cadence as the non-interactive New() loop.
+func (d *dashboard) sampleLoop() {
+	lastResolve := time.Now()
+	for {
+		d.mu.Lock()
+		paused := d.paused
+		iv := d.interval
+		d.mu.Unlock()
+
+		if time.Since(lastResolve) >= resolveInterval {
+			_ = d.resolve()
+			lastResolve = time.Now()
+		}
+
+		if !paused {
+			t := time.Since(d.pi.StartTime)
+			d.mu.Lock()
+			pids := append([]int32(nil), d.order...)
+			d.mu.Unlock()
+
+			for _, pid := range pids {
+				d.mu.Lock()
+				proc := d.procs[pid]
+				d.mu.Unlock()
+
+				s, err := sampleProcess(proc, t)
+				if err != nil {
+					continue
+				}
+				d.mu.Lock()
+				d.pi.Series[pid].Samples = append(d.pi.Series[pid].Samples, s)
+				d.mu.Unlock()
+				d.render(pid)
+			}
+		}
+		time.Sleep(iv)
+	}
+}
+
+// render draws pid's latest sample as a simple text readout in its tab;
+// full line charts are available at any time via the e (export PNG) hotkey.
+func (d *dashboard) render(pid int32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	series := d.pi.Series[pid]
+	view := d.views[pid]
+	if series == nil || view == nil || len(series.Samples) == 0 {
+		return
+	}
+	s := series.Samples[len(series.Samples)-1]
+	view.SetText(fmt.Sprintf(
+		"PID %d\nCPU: %.2f%%\nRSS: %d KB  VMS: %d KB\nIO read: %d  write: %d\nsamples: %d",
+		pid, s.cpu*100, s.rss/1024, s.vms/1024, s.readCount, s.writeCount, len(series.Samples)))
+}
+
+// switchTab moves the visible tab forward (delta > 0) or backward
+// (delta < 0), wrapping around, in response to Tab/Shift+Tab.
+func (d *dashboard) switchTab(delta int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.order) == 0 {
+		return
+	}
+	d.active = ((d.active+delta)%len(d.order) + len(d.order)) % len(d.order)
+	d.tabs.SwitchToPage(seriesTabName(d.order[d.active]))
+	d.setStatusLocked()
+}
+
+func (d *dashboard) adjustInterval(delta time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	iv := d.interval + delta
+	if iv < 10*time.Millisecond {
+		iv = 10 * time.Millisecond
+	}
+	d.interval = iv
+	d.setStatusLocked()
+}
+
+func (d *dashboard) setStatus() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.setStatusLocked()
+}
+
+// setStatusLocked renders the status bar; callers must already hold d.mu.
+func (d *dashboard) setStatusLocked() {
+	state := "running"
+	if d.paused {
+		state = "paused"
+	}
+	pid := int32(0)
+	if len(d.order) > 0 {
+		pid = d.order[d.active]
+	}
+	d.status.SetText(fmt.Sprintf("[%s] pid=%d (%d/%d) interval=%s  (p)ause/resume  (+/-) interval  (tab) switch pid  (e)xport PNG  (q)uit",
+		state, pid, d.active+1, len(d.order), d.interval))
+}
+
+// export writes the current buffered samples of every tracked PID to a
+// single PNG using the existing MemPlot/IoPlot/CpuPlot pipeline, without
+// interrupting live sampling.
+func (d *dashboard) export() error {
+	d.mu.Lock()
+	pi := &ProcessInfo{
+		StartTime:        d.pi.StartTime,
+		SamplingInterval: d.pi.SamplingInterval,
+		Series:           make(map[int32]*procSeries, len(d.pi.Series)),
+	}
+	for pid, series := range d.pi.Series {
+		pi.Series[pid] = &procSeries{
+			Pid:     series.Pid,
+			Name:    series.Name,
+			Samples: append([]sample(nil), series.Samples...),
+		}
+	}
+	d.mu.Unlock()
+
+	memPlot, err := pi.MemPlot(memMetricSet{"rss": true, "vms": true})
+	if err != nil {
+		return err
+	}
+	ioPlot, err := pi.IoPlot()
+	if err != nil {
+		return err
+	}
+	cpuPlot, err := pi.CpuPlot()
+	if err != nil {
+		return err
+	}
+	plots := [][]*plot.Plot{{memPlot}, {ioPlot}, {cpuPlot}}
+	return pi.Save(plots, d.imgWidth, d.imgHeight, d.output)
+}